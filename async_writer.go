@@ -0,0 +1,94 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by AsyncWriter.Write once the AsyncWriter has been
+// closed.
+var ErrClosed = errors.New("log: write to closed AsyncWriter")
+
+// AsyncWriter wraps an io.Writer, handing off each Write to a background
+// goroutine through a bounded channel, so a slow or blocking sink (a network
+// log collector, a file on a loaded disk) doesn't stall the caller. When the
+// channel is full, the record is dropped and counted rather than blocking;
+// use Dropped to monitor for this.
+//
+// Use NewAsyncWriter, and Close when done to flush and release the background
+// goroutine.
+type AsyncWriter struct {
+	w       io.Writer
+	records chan []byte
+	done    chan struct{}
+	dropped int64
+
+	mu        sync.RWMutex // Held for reading by Write, for writing by Close, so a Write in flight blocks Close from closing records underneath it.
+	closed    bool
+	closeOnce sync.Once
+}
+
+// NewAsyncWriter returns an AsyncWriter wrapping w, queuing up to queueSize
+// pending writes before it starts dropping records.
+func NewAsyncWriter(w io.Writer, queueSize int) *AsyncWriter {
+	aw := &AsyncWriter{
+		w:       w,
+		records: make(chan []byte, queueSize),
+		done:    make(chan struct{}),
+	}
+	go aw.run()
+	return aw
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.done)
+	for buf := range aw.records {
+		aw.w.Write(buf)
+	}
+}
+
+// Write implements io.Writer. It never blocks: if the queue is full, the
+// record is dropped and Dropped's counter is incremented. The written bytes
+// are copied, so the caller's buffer can be reused immediately. Write returns
+// ErrClosed once the AsyncWriter has been closed.
+func (aw *AsyncWriter) Write(buf []byte) (int, error) {
+	aw.mu.RLock()
+	defer aw.mu.RUnlock()
+	if aw.closed {
+		return 0, ErrClosed
+	}
+
+	record := append([]byte(nil), buf...)
+	select {
+	case aw.records <- record:
+	default:
+		atomic.AddInt64(&aw.dropped, 1)
+	}
+	return len(buf), nil
+}
+
+// Dropped returns the number of records dropped so far because the queue was full.
+func (aw *AsyncWriter) Dropped() int64 {
+	return atomic.LoadInt64(&aw.dropped)
+}
+
+// Close stops accepting new writes, waits for queued records to be written to
+// the underlying writer, and closes it if it implements io.Closer. Close is
+// safe to call more than once; only the first call does the work.
+func (aw *AsyncWriter) Close() error {
+	var err error
+	aw.closeOnce.Do(func() {
+		aw.mu.Lock()
+		aw.closed = true
+		aw.mu.Unlock()
+
+		close(aw.records)
+		<-aw.done
+		if c, ok := aw.w.(io.Closer); ok {
+			err = c.Close()
+		}
+	})
+	return err
+}
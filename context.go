@@ -0,0 +1,22 @@
+package log
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, for later retrieval with
+// FromContext. Typical use is to attach a request-scoped logger (e.g. one
+// carrying a "request_id" tag through With) to the context passed down a
+// call chain.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger previously stored in ctx with NewContext,
+// or the default logger if ctx carries none.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return std
+}
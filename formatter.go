@@ -0,0 +1,171 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// builderPool reduces per-call allocations in TextFormatter and
+// LogfmtFormatter.
+var builderPool = sync.Pool{
+	New: func() interface{} { return &strings.Builder{} },
+}
+
+// objectPool reduces per-call allocations in JSONFormatter.
+var objectPool = sync.Pool{
+	New: func() interface{} { return map[string]interface{}{} },
+}
+
+// Formatter turns an Entry into the bytes a Logger writes to its output,
+// including the trailing newline. Implementations must be safe to call with
+// different Entries; a Logger's formatter is not otherwise synchronized.
+type Formatter interface {
+	Format(entry *Entry) ([]byte, error)
+}
+
+// TextFormatter formats an Entry the way the Go standard library log package
+// does, with tags appended as a "(k=v ...)" suffix. It is the default
+// Formatter.
+type TextFormatter struct{}
+
+// Format implements Formatter.
+func (TextFormatter) Format(entry *Entry) ([]byte, error) {
+	b := builderPool.Get().(*strings.Builder)
+	b.Reset()
+	defer builderPool.Put(b)
+
+	if !entry.Time.IsZero() {
+		b.WriteString(entry.Time.Format(time.RFC3339Nano) + " ")
+	}
+
+	b.WriteString(entry.Prefix)
+
+	if entry.File != "" {
+		fmt.Fprintf(b, "%s: ", entry.File)
+	}
+
+	b.WriteString(entry.Message)
+
+	prefix := " ("
+	for k, v := range entry.Tags {
+		fmt.Fprintf(b, "%s%s=%v", prefix, k, v)
+		prefix = " "
+	}
+	if prefix != " (" {
+		b.WriteString(")")
+	}
+	for _, fr := range entry.Stack {
+		fmt.Fprintf(b, "\n    %s\n        %s:%d", fr.Function, fr.File, fr.Line)
+	}
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// JSONFormatter formats an Entry as a single line of JSON, with fields
+// "message", "file" (if set), "level", "timestamp" (if set), "stack" (if set)
+// and all tags.
+type JSONFormatter struct{}
+
+// Format implements Formatter.
+func (JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	o := objectPool.Get().(map[string]interface{})
+	defer func() {
+		for k := range o {
+			delete(o, k)
+		}
+		objectPool.Put(o)
+	}()
+
+	o["message"] = entry.Message
+	o["level"] = entry.Level.String()
+	if !entry.Time.IsZero() {
+		o["timestamp"] = entry.Time.Format(time.RFC3339Nano)
+	}
+	if entry.File != "" {
+		o["file"] = entry.File + ": "
+	}
+	for k, v := range entry.Tags {
+		o[k] = v
+	}
+	if len(entry.Stack) > 0 {
+		o["stack"] = entry.Stack
+	}
+
+	buf, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+// LogfmtFormatter formats an Entry as a single line of logfmt, the key=value
+// format used by go-kit and Heroku's logplex
+// (https://brandur.org/logfmt), with fields "ts", "level", "file" and "msg",
+// followed by tags in sorted key order and a "stack" field if set.
+type LogfmtFormatter struct{}
+
+// Format implements Formatter.
+func (LogfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	b := builderPool.Get().(*strings.Builder)
+	b.Reset()
+	defer builderPool.Put(b)
+
+	first := true
+	pair := func(k, v string) {
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(v))
+	}
+
+	if !entry.Time.IsZero() {
+		pair("ts", entry.Time.Format(time.RFC3339Nano))
+	}
+	pair("level", entry.Level.String())
+	if entry.File != "" {
+		pair("file", entry.File)
+	}
+	pair("msg", entry.Message)
+
+	keys := make([]string, 0, len(entry.Tags))
+	for k := range entry.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pair(k, fmt.Sprintf("%v", entry.Tags[k]))
+	}
+
+	if len(entry.Stack) > 0 {
+		frames := make([]string, len(entry.Stack))
+		for i, fr := range entry.Stack {
+			frames[i] = fmt.Sprintf("%s %s:%d", fr.Function, fr.File, fr.Line)
+		}
+		pair("stack", strings.Join(frames, " | "))
+	}
+
+	b.WriteByte('\n')
+	return []byte(b.String()), nil
+}
+
+// logfmtValue quotes v if it is empty, or contains a space, tab, newline,
+// '=' or '"'.
+func logfmtValue(v string) string {
+	if v == "" {
+		return `""`
+	}
+	if strings.ContainsAny(v, " =\"\t\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mjl-/log/fur"
+)
+
+func benchEntry() *Entry {
+	return &Entry{
+		Message: "processing request: connect to remote: dial tcp: connection refused",
+		Time:    time.Now(),
+		Level:   LevelError,
+		File:    "handler.go:123",
+		Tags:    fur.Tags{"address": "10.0.0.1:5432", "id": 123, "attempt": 3},
+	}
+}
+
+func BenchmarkTextFormatter(b *testing.B) {
+	f := &TextFormatter{}
+	entry := benchEntry()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	f := &JSONFormatter{}
+	entry := benchEntry()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLogfmtFormatter(b *testing.B) {
+	f := &LogfmtFormatter{}
+	entry := benchEntry()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Format(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mjl-/log/fur"
+)
+
+var errBoom = errors.New("boom")
+
+func TestJSONFormatter(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", 0)
+	l.SetFormatter(&JSONFormatter{})
+	l.Errorf("open resource: %w", fur.Errorf("connect: %w", errBoom).Tag("address", "x"))
+
+	var o map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &o); err != nil {
+		t.Fatalf("unmarshal json: %s", err)
+	}
+	if o["level"] != "error" {
+		t.Fatalf("got level %v, expected error", o["level"])
+	}
+	if o["address"] != "x" {
+		t.Fatalf("got address %v, expected x", o["address"])
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", 0)
+	l.SetFormatter(&LogfmtFormatter{})
+	l.Infof("hello %s", "world")
+
+	s := buf.String()
+	if !strings.Contains(s, `level=info`) || !strings.Contains(s, `msg="hello world"`) {
+		t.Fatalf("got %q, missing expected fields", s)
+	}
+}
+
+func TestLogfmtValueQuoting(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"", `""`},
+		{"plain", "plain"},
+		{"has space", `"has space"`},
+		{`has"quote`, `"has\"quote"`},
+	}
+	for _, c := range cases {
+		if got := logfmtValue(c.in); got != c.out {
+			t.Errorf("logfmtValue(%q) = %q, expected %q", c.in, got, c.out)
+		}
+	}
+}
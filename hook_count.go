@@ -0,0 +1,42 @@
+package log
+
+import "sync"
+
+// CountHook counts the number of records fired at each of its Levels,
+// similar to a Prometheus counter vector labeled by level. The zero value is
+// not usable; use NewCountHook.
+type CountHook struct {
+	levels []Level
+
+	mu     sync.Mutex
+	counts map[Level]int64
+}
+
+// NewCountHook returns a hook that counts records at levels. If levels is
+// empty, all levels are counted.
+func NewCountHook(levels ...Level) *CountHook {
+	if len(levels) == 0 {
+		levels = []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+	}
+	return &CountHook{levels: levels, counts: map[Level]int64{}}
+}
+
+// Levels implements Hook.
+func (h *CountHook) Levels() []Level {
+	return h.levels
+}
+
+// Fire implements Hook.
+func (h *CountHook) Fire(entry Entry) error {
+	h.mu.Lock()
+	h.counts[entry.Level]++
+	h.mu.Unlock()
+	return nil
+}
+
+// Count returns the number of records fired at level so far.
+func (h *CountHook) Count(level Level) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counts[level]
+}
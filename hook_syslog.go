@@ -0,0 +1,47 @@
+//go:build !windows && !plan9 && !js
+
+package log
+
+import "log/syslog"
+
+// SyslogHook forwards records to a syslog daemon, picking the syslog severity
+// from the record's Level.
+type SyslogHook struct {
+	w      *syslog.Writer
+	levels []Level
+}
+
+// NewSyslogHook dials a syslog daemon as with syslog.Dial, and returns a hook
+// firing for levels. If levels is empty, all levels are forwarded.
+//
+// network and raddr are passed to syslog.Dial; use network "" to log to the
+// local syslog daemon.
+func NewSyslogHook(network, raddr string, priority syslog.Priority, tag string, levels ...Level) (*SyslogHook, error) {
+	w, err := syslog.Dial(network, raddr, priority, tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(levels) == 0 {
+		levels = []Level{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+	}
+	return &SyslogHook{w, levels}, nil
+}
+
+// Levels implements Hook.
+func (h *SyslogHook) Levels() []Level {
+	return h.levels
+}
+
+// Fire implements Hook, writing entry to syslog at a severity matching its Level.
+func (h *SyslogHook) Fire(entry Entry) error {
+	switch entry.Level {
+	case LevelDebug:
+		return h.w.Debug(entry.Message)
+	case LevelInfo:
+		return h.w.Info(entry.Message)
+	case LevelWarn:
+		return h.w.Warning(entry.Message)
+	default:
+		return h.w.Err(entry.Message)
+	}
+}
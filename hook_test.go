@@ -0,0 +1,126 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type recordingHook struct {
+	levels  []Level
+	entries []Entry
+	err     error
+}
+
+func (h *recordingHook) Levels() []Level { return h.levels }
+
+func (h *recordingHook) Fire(entry Entry) error {
+	h.entries = append(h.entries, entry)
+	return h.err
+}
+
+func TestHookLevels(t *testing.T) {
+	l := New(&discard{}, "", 0)
+	hook := &recordingHook{levels: []Level{LevelError}}
+	l.AddHook(hook)
+
+	l.Infof("info message")
+	l.Warnf("warn message")
+	l.Errorf("error message")
+
+	if len(hook.entries) != 1 {
+		t.Fatalf("got %d entries, expected 1", len(hook.entries))
+	}
+	if hook.entries[0].Level != LevelError {
+		t.Fatalf("got level %v, expected LevelError", hook.entries[0].Level)
+	}
+	if hook.entries[0].Message != "error message" {
+		t.Fatalf("got message %q, expected %q", hook.entries[0].Message, "error message")
+	}
+}
+
+func TestHookFailureIsolation(t *testing.T) {
+	l := New(&discard{}, "", 0)
+	failing := &recordingHook{levels: []Level{LevelInfo}, err: errors.New("sink unavailable")}
+	working := &recordingHook{levels: []Level{LevelInfo}}
+	l.AddHook(failing)
+	l.AddHook(working)
+
+	l.Infof("hello")
+
+	if len(failing.entries) != 1 {
+		t.Fatalf("failing hook got %d entries, expected 1", len(failing.entries))
+	}
+	if len(working.entries) != 1 {
+		t.Fatalf("working hook got %d entries, expected 1, a failing hook must not stop others", len(working.entries))
+	}
+}
+
+func TestClearHooks(t *testing.T) {
+	l := New(&discard{}, "", 0)
+	hook := &recordingHook{levels: []Level{LevelInfo}}
+	l.AddHook(hook)
+	l.ClearHooks()
+
+	l.Infof("hello")
+
+	if len(hook.entries) != 0 {
+		t.Fatalf("got %d entries after ClearHooks, expected 0", len(hook.entries))
+	}
+}
+
+func TestCountHook(t *testing.T) {
+	l := New(&discard{}, "", 0)
+	hook := NewCountHook()
+	l.AddHook(hook)
+
+	l.Infof("a")
+	l.Infof("b")
+	l.Errorf("c")
+
+	if got := hook.Count(LevelInfo); got != 2 {
+		t.Fatalf("got %d info records, expected 2", got)
+	}
+	if got := hook.Count(LevelError); got != 1 {
+		t.Fatalf("got %d error records, expected 1", got)
+	}
+	if got := hook.Count(LevelWarn); got != 0 {
+		t.Fatalf("got %d warn records, expected 0", got)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", 0)
+	hook := &recordingHook{levels: []Level{LevelDebug, LevelInfo, LevelWarn, LevelError}}
+	l.AddHook(hook)
+
+	l.SetLevel(LevelWarn)
+	if got := l.GetLevel(); got != LevelWarn {
+		t.Fatalf("got level %v, expected LevelWarn", got)
+	}
+
+	l.Debugf("debug message")
+	l.Infof("info message")
+
+	if buf.Len() != 0 {
+		t.Fatalf("got formatted output %q for records below LevelWarn", buf.String())
+	}
+	if len(hook.entries) != 0 {
+		t.Fatalf("got %d hook entries, expected 0 for records below LevelWarn", len(hook.entries))
+	}
+
+	l.Warnf("warn message")
+	l.Errorf("error message")
+
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Fatalf("got %d logged lines, expected 2 (warn and error)", got)
+	}
+	if len(hook.entries) != 2 {
+		t.Fatalf("got %d hook entries, expected 2 (warn and error)", len(hook.entries))
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
@@ -3,11 +3,24 @@
 // Log is similar to the Go standard library log package, with the following major
 // changes:
 //
-//	- Log has a flag to log as JSON.
-//	- Log unwraps errors and prints key/value pairs (tags) if they implement the
-//	  fur.Tagger interface.
-//	- Log only has Printf-variants, not Println and Print, but always writes an
-//	  ending newline.
+//   - Log has a pluggable Formatter, with TextFormatter (the default), JSONFormatter
+//     and LogfmtFormatter built in.
+//   - Log unwraps errors and prints key/value pairs (tags) if they implement the
+//     fur.Tagger interface.
+//   - Log only has Printf-variants, not Println and Print, but always writes an
+//     ending newline.
+//   - Log has Debugf/Infof/Warnf/Errorf/Fatalf methods for the level of a
+//     record, and a minimum level can be set with SetLevel to drop noisy
+//     records before they are formatted.
+//   - Log has With/WithPairs to create a child logger that merges additional
+//     tags into every record it logs, and NewContext/FromContext to carry such
+//     a logger on a context.Context.
+//   - Log is safe for concurrent use: writes to the output are serialized,
+//     setters like SetLevel/SetOutput/SetFormatter may run concurrently with
+//     logging calls, and AsyncWriter can wrap an output to hand writes off to
+//     a background goroutine.
+//   - Log can capture a stack trace from an xerrors.Formatter error chain with
+//     FlagStack, for inclusion in the Entry passed to Formatters and Hooks.
 //
 // Example usage:
 //
@@ -24,13 +37,12 @@
 package log
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"runtime"
-	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/xerrors"
@@ -42,6 +54,40 @@ var (
 	std = New(os.Stderr, "", 0)
 )
 
+// Level indicates the severity of a log record, from Debug (least severe) to
+// Fatal (most severe). A Logger can be configured with a minimum Level through
+// SetLevel, causing records below that level to be dropped before they are
+// formatted.
+type Level int
+
+// Levels, ordered from least to most severe. The zero value is LevelDebug, so
+// a Logger that hasn't called SetLevel logs everything.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// String returns the lower-case name of the level, as used in the "level"
+// field of JSON output.
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelFatal:
+		return "fatal"
+	}
+	return "unknown"
+}
+
 // SetFlags sets new flags for the default logger.
 // Default is 0.
 func SetFlags(flags int) {
@@ -60,184 +106,421 @@ func SetOutput(out io.Writer) {
 	std.SetOutput(out)
 }
 
-// Printf logs a message to the default logger.
+// SetOutputLocked sets a new output for the default logger, see
+// (*Logger).SetOutputLocked.
+func SetOutputLocked(out io.Writer) {
+	std.SetOutputLocked(out)
+}
+
+// SetLevel sets the minimum level for the default logger. Records below this
+// level are dropped. Default is LevelDebug, logging everything.
+func SetLevel(level Level) {
+	std.SetLevel(level)
+}
+
+// GetLevel returns the minimum level of the default logger.
+func GetLevel() Level {
+	return std.GetLevel()
+}
+
+// SetFormatter sets a new formatter for the default logger.
+func SetFormatter(formatter Formatter) {
+	std.SetFormatter(formatter)
+}
+
+// With returns the default logger's child logger, see (*Logger).With.
+func With(tags fur.Tags) *Logger {
+	return std.With(tags)
+}
+
+// WithPairs returns the default logger's child logger, see (*Logger).WithPairs.
+func WithPairs(keyvals ...interface{}) *Logger {
+	return std.WithPairs(keyvals...)
+}
+
+// Debugf logs a debug message to the default logger.
+func Debugf(format string, args ...interface{}) {
+	std.write(LevelDebug, format, args, 2)
+}
+
+// Infof logs an informational message to the default logger.
+func Infof(format string, args ...interface{}) {
+	std.write(LevelInfo, format, args, 2)
+}
+
+// Warnf logs a warning message to the default logger.
+func Warnf(format string, args ...interface{}) {
+	std.write(LevelWarn, format, args, 2)
+}
+
+// Errorf logs an error message to the default logger.
+func Errorf(format string, args ...interface{}) {
+	std.write(LevelError, format, args, 2)
+}
+
+// Printf logs a message to the default logger, at LevelInfo.
 func Printf(format string, args ...interface{}) {
-	std.write(format, args, 2)
+	std.write(LevelInfo, format, args, 2)
 }
 
 // Fatalf logs a message to the default logger and quits the program with exit status 1.
 func Fatalf(format string, args ...interface{}) {
-	std.write(format, args, 2)
+	std.write(LevelFatal, format, args, 2)
 	os.Exit(1)
 }
 
 // Panicf logs a message to the default logger and calls panic.
 func Panicf(format string, args ...interface{}) {
-	s := std.write(format, args, 2)
+	s := std.write(LevelError, format, args, 2)
 	panic(s)
 }
 
 // Logger provides functions for logging.
 type Logger struct {
+	state *outputState // Shared with loggers derived through With/WithPairs, so writes to the same out are serialized.
+
+	cfgMu     sync.Mutex // Guards the fields below, so a setter racing with a log call doesn't corrupt or torn-read them.
+	prefix    string
+	flags     int
+	level     Level
+	hooks     []Hook
+	tags      fur.Tags // Tags set through With, merged into every record.
+	formatter Formatter
+}
+
+// outputState holds a Logger's output writer and the lock serializing writes
+// to it, so concurrent calls don't interleave bytes mid-line. It is shared by
+// pointer between a Logger and any children derived through With/WithPairs.
+type outputState struct {
+	mu     sync.Mutex
 	out    io.Writer
-	prefix string
-	flags  int
+	locked bool // If set, out is already safe for concurrent Write, see SetOutputLocked.
 }
 
-// FlagTimestamp and other flags influence the fields written to out for a log message.
+func (s *outputState) write(buf []byte) {
+	s.mu.Lock()
+	out, locked := s.out, s.locked
+	if !locked {
+		defer s.mu.Unlock()
+		out.Write(buf)
+		return
+	}
+	s.mu.Unlock()
+	out.Write(buf)
+}
+
+// FlagTimestamp and other flags influence the fields a Logger includes in the
+// Entry it builds for each record, which its Formatter then renders.
 const (
-	FlagTimestamp = 1 << iota // Print timestamp in local time zone, formatted with time.RFC3339Nano.
-	FlagUTC                   // If printing timestamp, print in UTC.
-	FlagFile                  // Filename with line number.
-	FlagPath                  // Full path with line number.
-
-	// Log a message on a single line in JSON format, with fields "message",
-	// "timestamp", "file", "level" ("info" or "error", depending on whether the
-	// message contains a wrapped error) and all (unwrapped) tags.
-	FlagJSON
+	FlagTimestamp = 1 << iota // Include a timestamp, in local time zone, formatted with time.RFC3339Nano.
+	FlagUTC                   // If including a timestamp, use UTC instead of local time.
+	FlagFile                  // Include the filename with line number of the caller.
+	FlagPath                  // Include the full path with line number of the caller.
+	FlagStack                 // Include a stack trace, gathered from the wrapped xerrors.Formatter chain.
 )
 
-// New returns a new logger.
+// Entry is a single log record, as passed to a Formatter and to a Hook's Fire
+// method.
+type Entry struct {
+	Message string    // Formatted message, tags not included.
+	Time    time.Time // When the record was logged. Zero if FlagTimestamp isn't set.
+	Level   Level
+	File    string       // "file:line" of the caller, empty unless FlagFile or FlagPath is set.
+	Tags    fur.Tags     // Tags merged from With/WithPairs and the chain of wrapped errors.
+	Prefix  string       // The Logger's prefix, for Formatters that render one.
+	Stack   []StackFrame // Stack frames gathered from the error chain, only set if FlagStack is set.
+}
+
+// Hook receives a copy of every Entry logged at one of its Levels. Hooks are
+// meant for fanning log records out to external sinks (syslog, a metrics
+// counter, an error tracker) without having to wrap the Logger's io.Writer.
+type Hook interface {
+	// Levels returns the levels this hook wants to receive.
+	Levels() []Level
+
+	// Fire is called synchronously for each Entry at one of the hook's
+	// Levels. An error does not stop logging or other hooks from running; it
+	// is printed to os.Stderr.
+	Fire(entry Entry) error
+}
+
+// AddHook registers a hook on the default logger.
+func AddHook(hook Hook) {
+	std.AddHook(hook)
+}
+
+// ClearHooks removes all hooks from the default logger.
+func ClearHooks() {
+	std.ClearHooks()
+}
+
+// AddHook registers a hook that is fired for each Entry at one of the levels
+// the hook declares through Levels.
+func (l *Logger) AddHook(hook Hook) {
+	l.cfgMu.Lock()
+	l.hooks = append(l.hooks, hook)
+	l.cfgMu.Unlock()
+}
+
+// ClearHooks removes all hooks from the logger.
+func (l *Logger) ClearHooks() {
+	l.cfgMu.Lock()
+	l.hooks = nil
+	l.cfgMu.Unlock()
+}
+
+// fireHooks runs hooks (a snapshot taken by the caller) against entry.
+func fireHooks(hooks []Hook, entry Entry) {
+	for _, hook := range hooks {
+		var wants bool
+		for _, lv := range hook.Levels() {
+			if lv == entry.Level {
+				wants = true
+				break
+			}
+		}
+		if !wants {
+			continue
+		}
+		if err := hook.Fire(entry); err != nil {
+			fmt.Fprintf(os.Stderr, "log hook %T: %s\n", hook, err)
+		}
+	}
+}
+
+// New returns a new logger. The level defaults to LevelDebug, logging
+// everything; use SetLevel to raise it. The formatter defaults to
+// &TextFormatter{}; use SetFormatter to log as JSON, logfmt, or a custom
+// format.
 func New(out io.Writer, prefix string, flags int) *Logger {
 	return &Logger{
-		out:    out,
-		prefix: prefix,
-		flags:  flags,
+		state:     &outputState{out: out},
+		prefix:    prefix,
+		flags:     flags,
+		level:     LevelDebug,
+		formatter: &TextFormatter{},
 	}
 }
 
 // SetFlags modifies log printing flags on the logger.
 func (l *Logger) SetFlags(flags int) {
+	l.cfgMu.Lock()
 	l.flags = flags
+	l.cfgMu.Unlock()
 }
 
 // SetPrefix sets a new prefix.
 func (l *Logger) SetPrefix(prefix string) {
+	l.cfgMu.Lock()
 	l.prefix = prefix
+	l.cfgMu.Unlock()
 }
 
-// SetOutput sets a new writer where logs will be written to.
-// Log does one write at a time, with text ending in a newline.
+// SetOutput sets a new writer where logs will be written to. Writes are
+// serialized with a mutex so concurrent log calls don't interleave bytes
+// mid-line. Use SetOutputLocked for a writer that is already safe for
+// concurrent Write calls, to skip that locking.
 func (l *Logger) SetOutput(out io.Writer) {
-	l.out = out
+	l.state.mu.Lock()
+	l.state.out = out
+	l.state.locked = false
+	l.state.mu.Unlock()
 }
 
-// Printf formats its parameters and prints them.
-func (l *Logger) Printf(format string, args ...interface{}) {
-	l.write(format, args, 2)
+// SetOutputLocked sets a new writer where logs will be written to, like
+// SetOutput, but without serializing writes to it with a mutex. Only use this
+// for a writer that is already safe for concurrent Write calls, e.g.
+// os.Stderr on POSIX systems for writes not larger than PIPE_BUF.
+func (l *Logger) SetOutputLocked(out io.Writer) {
+	l.state.mu.Lock()
+	l.state.out = out
+	l.state.locked = true
+	l.state.mu.Unlock()
 }
 
-// Fatalf prints a log message and exits the program.
-func (l *Logger) Fatalf(format string, args ...interface{}) {
-	l.write(format, args, 2)
-	os.Exit(1)
+// SetLevel sets the minimum level for this logger. Records below this level
+// are dropped before they are formatted.
+func (l *Logger) SetLevel(level Level) {
+	l.cfgMu.Lock()
+	l.level = level
+	l.cfgMu.Unlock()
 }
 
-// Panicf prints a log message and calls panic.
-func (l *Logger) Panicf(format string, args ...interface{}) {
-	s := l.write(format, args, 2)
-	panic(s)
+// GetLevel returns the minimum level for this logger.
+func (l *Logger) GetLevel() Level {
+	l.cfgMu.Lock()
+	defer l.cfgMu.Unlock()
+	return l.level
 }
 
-func (l *Logger) write(format string, args []interface{}, calldepth int) string {
-	if l.flags&FlagJSON != 0 {
-		return l.writeJSON(format, args, calldepth+1)
-	}
+// SetFormatter sets a new formatter for this logger. Default is &TextFormatter{}.
+func (l *Logger) SetFormatter(formatter Formatter) {
+	l.cfgMu.Lock()
+	l.formatter = formatter
+	l.cfgMu.Unlock()
+}
 
-	b := &strings.Builder{}
-	if l.flags&FlagTimestamp != 0 {
-		now := time.Now()
-		if l.flags&FlagUTC != 0 {
-			now = now.UTC()
-		}
-		b.WriteString(now.Format(time.RFC3339Nano) + " ")
+// With returns a child logger that shares this logger's output and starts
+// with its own copy of this logger's prefix, flags, level, hooks and
+// formatter, as set at the time of the call; setters called on either logger
+// afterwards don't affect the other. The child merges tags into every record
+// it emits, in both the text
+// "(k=v ...)" suffix and the JSON output. On key collision with tags set on
+// an ancestor logger (through a prior With/WithPairs), the child's tags win.
+func (l *Logger) With(tags fur.Tags) *Logger {
+	l.cfgMu.Lock()
+	prefix, flags, level, formatter := l.prefix, l.flags, l.level, l.formatter
+	hooks := append([]Hook(nil), l.hooks...)
+	merged := fur.Tags{}
+	for k, v := range l.tags {
+		merged[k] = v
 	}
+	l.cfgMu.Unlock()
 
-	b.WriteString(l.prefix)
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return &Logger{
+		state:     l.state,
+		prefix:    prefix,
+		flags:     flags,
+		level:     level,
+		hooks:     hooks,
+		tags:      merged,
+		formatter: formatter,
+	}
+}
 
-	if l.flags&(FlagFile|FlagPath) != 0 {
-		_, file, line, ok := runtime.Caller(calldepth)
-		if ok {
-			if l.flags&FlagPath == 0 {
-				_, file = path.Split(file)
-			}
-			fmt.Fprintf(b, "%s:%d: ", file, line)
+// WithPairs is like With, taking alternating keys and values instead of a
+// fur.Tags map. A key without a matching value is logged with value
+// "!MISSING".
+func (l *Logger) WithPairs(keyvals ...interface{}) *Logger {
+	tags := fur.Tags{}
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprintf("%v", keyvals[i])
+		if i+1 < len(keyvals) {
+			tags[key] = keyvals[i+1]
+		} else {
+			tags[key] = "!MISSING"
 		}
 	}
+	return l.With(tags)
+}
 
-	err := xerrors.Errorf(format, args...)
-	b.WriteString(err.Error())
-	err = xerrors.Unwrap(err)
+// Debugf formats and prints its parameters at LevelDebug.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.write(LevelDebug, format, args, 2)
+}
+
+// Infof formats and prints its parameters at LevelInfo.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.write(LevelInfo, format, args, 2)
+}
+
+// Warnf formats and prints its parameters at LevelWarn.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.write(LevelWarn, format, args, 2)
+}
+
+// Errorf formats and prints its parameters at LevelError.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.write(LevelError, format, args, 2)
+}
+
+// Printf formats its parameters and prints them at LevelInfo.
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.write(LevelInfo, format, args, 2)
+}
 
-	prefix := " ("
+// Fatalf prints a log message at LevelFatal and exits the program.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.write(LevelFatal, format, args, 2)
+	os.Exit(1)
+}
+
+// Panicf prints a log message at LevelError and calls panic.
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	s := l.write(LevelError, format, args, 2)
+	panic(s)
+}
+
+// collectTags walks the chain of wrapped errors and merges the tags of those
+// that implement fur.Tagger, in unwrap order, for inclusion in an Entry.
+func collectTags(err error) fur.Tags {
+	tags := fur.Tags{}
 	for ; err != nil; err = xerrors.Unwrap(err) {
 		e, ok := err.(fur.Tagger)
 		if !ok {
 			continue
 		}
 		for k, v := range e.Tags() {
-			fmt.Fprintf(b, "%s%s=%v", prefix, k, v)
-			prefix = " "
+			tags[k] = v
 		}
 	}
-	if prefix != " (" {
-		b.WriteString(")")
-	}
-	s := b.String()
-	if !strings.HasSuffix(s, "\n") {
-		s += "\n"
-	}
-	l.out.Write([]byte(s))
-	return s
+	return tags
 }
 
-func (l *Logger) writeJSON(format string, args []interface{}, calldepth int) string {
-	o := map[string]interface{}{}
+func (l *Logger) write(level Level, format string, args []interface{}, calldepth int) string {
+	l.cfgMu.Lock()
+	cfgLevel, flags, prefix, hooks, formatter := l.level, l.flags, l.prefix, l.hooks, l.formatter
+	tags := fur.Tags{}
+	for k, v := range l.tags {
+		tags[k] = v
+	}
+	l.cfgMu.Unlock()
+
+	if level < cfgLevel {
+		return ""
+	}
 
 	err := xerrors.Errorf(format, args...)
 	msg := err.Error()
-	o["message"] = msg
-
-	if l.flags&FlagTimestamp != 0 {
-		now := time.Now()
-		if l.flags&FlagUTC != 0 {
-			now = now.UTC()
+	for k, v := range collectTags(xerrors.Unwrap(err)) {
+		if _, ok := tags[k]; !ok {
+			tags[k] = v
 		}
-		o["timestamp"] = now.Format(time.RFC3339Nano)
 	}
 
-	if l.flags&(FlagFile|FlagPath) != 0 {
-		_, file, line, ok := runtime.Caller(calldepth)
+	var file string
+	if flags&(FlagFile|FlagPath) != 0 {
+		_, f, line, ok := runtime.Caller(calldepth)
 		if ok {
-			if l.flags&FlagPath == 0 {
-				_, file = path.Split(file)
+			if flags&FlagPath == 0 {
+				_, f = path.Split(f)
 			}
-			o["file"] = fmt.Sprintf("%s:%d: ", file, line)
+			file = fmt.Sprintf("%s:%d", f, line)
 		}
 	}
 
-	err = xerrors.Unwrap(err)
-	if err == nil {
-		o["level"] = "info"
-	} else {
-		o["level"] = "error"
-	}
-	for ; err != nil; err = xerrors.Unwrap(err) {
-		e, ok := err.(fur.Tagger)
-		if !ok {
-			continue
-		}
-		for k, v := range e.Tags() {
-			o[k] = v
+	var t time.Time
+	if flags&FlagTimestamp != 0 {
+		t = time.Now()
+		if flags&FlagUTC != 0 {
+			t = t.UTC()
 		}
 	}
 
-	buf, err := json.Marshal(o)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "marshal json for message %q: %s\n", msg, err)
+	var stack []StackFrame
+	if flags&FlagStack != 0 {
+		stack = collectStack(err)
+	}
+
+	entry := Entry{
+		Message: msg,
+		Time:    t,
+		Level:   level,
+		File:    file,
+		Tags:    tags,
+		Prefix:  prefix,
+		Stack:   stack,
+	}
+	fireHooks(hooks, entry)
+
+	buf, ferr := formatter.Format(&entry)
+	if ferr != nil {
+		fmt.Fprintf(os.Stderr, "format log message %q: %s\n", msg, ferr)
 		return msg
 	}
-	s := string(buf)
-	l.out.Write([]byte(s + "\n"))
-	return s
+	l.state.write(buf)
+	return string(buf)
 }
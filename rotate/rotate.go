@@ -0,0 +1,260 @@
+// Package rotate provides an io.Writer that rotates its underlying file based
+// on size and age, for use as the output of a log.Logger (or anything else
+// writing lines to a file). It plays the same role as the file appenders
+// found in log4go and lumberjack.
+//
+// A SIGHUP reopens the current file, for compatibility with external
+// logrotate-style tools that rename the file out from under the process.
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures rotation behavior for a Writer. The zero value disables
+// the corresponding limit, e.g. MaxSize 0 means the file is never rotated for
+// size.
+type Options struct {
+	MaxSize    int64         // Rotate once the file would grow beyond MaxSize bytes.
+	MaxAge     time.Duration // Remove rotated files older than MaxAge.
+	MaxBackups int           // Keep at most MaxBackups rotated files, removing the oldest first.
+	Compress   bool          // Gzip rotated files.
+}
+
+// Writer is an io.WriteCloser that writes to a file, rotating it according to
+// its Options. It is safe for concurrent use. A SIGHUP causes the file to be
+// reopened (see package doc).
+type Writer struct {
+	filename string
+	opts     Options
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+
+	unix unixState // Platform-specific SIGHUP handling, see rotate_unix.go/rotate_windows.go.
+}
+
+// New opens (or creates) filename for appending, and returns a Writer with
+// the given Options.
+func New(filename string, opts Options) (*Writer, error) {
+	w := &Writer{filename: filename, opts: opts}
+	if err := w.openCurrent(); err != nil {
+		return nil, err
+	}
+	w.watchHUP()
+	return w, nil
+}
+
+func (w *Writer) openCurrent() error {
+	f, err := os.OpenFile(w.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	w.f = f
+	w.size = fi.Size()
+	return nil
+}
+
+// Write implements io.Writer, rotating first if p would grow the file beyond
+// Options.MaxSize. An empty file is not rotated, so a single write larger
+// than Options.MaxSize still goes into the current file.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, moves it aside with a timestamp suffix
+// (optionally gzip-compressing it), opens a new current file, and removes
+// backups beyond Options.MaxBackups or older than Options.MaxAge. It is
+// called automatically by Write when the file grows beyond Options.MaxSize.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+func (w *Writer) rotate() error {
+	if w.f != nil {
+		if err := w.f.Sync(); err != nil {
+			return fmt.Errorf("sync log file before rotating: %w", err)
+		}
+		if err := w.f.Close(); err != nil {
+			return fmt.Errorf("close log file before rotating: %w", err)
+		}
+		w.f = nil
+	}
+
+	if _, err := os.Stat(w.filename); err == nil {
+		backup := w.filename + "." + time.Now().Format("20060102T150405.000000000")
+		if err := os.Rename(w.filename, backup); err != nil {
+			return fmt.Errorf("rename log file for rotation: %w", err)
+		}
+		if w.opts.Compress {
+			if err := compressFile(backup); err != nil {
+				return fmt.Errorf("compress rotated log file: %w", err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("stat log file for rotation: %w", err)
+	}
+
+	if err := w.openCurrent(); err != nil {
+		return err
+	}
+	// A freshly rotated file starts empty; fsync it and, separately, the
+	// directory entry openCurrent created, so the rotation boundary survives a
+	// crash (fsyncing the file alone does not make a rename/create durable).
+	if err := w.f.Sync(); err != nil {
+		return fmt.Errorf("sync log file after rotating: %w", err)
+	}
+	if err := syncDir(w.filename); err != nil {
+		return fmt.Errorf("sync log directory after rotating: %w", err)
+	}
+	w.size = 0
+
+	return w.cleanupBackups()
+}
+
+// syncDir fsyncs the directory containing name, so a rename or create of name
+// is durable across a crash.
+func syncDir(name string) error {
+	d, err := os.Open(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func compressFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(out)
+	if _, err := io.Copy(gw, in); err != nil {
+		gw.Close()
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// cleanupBackups removes rotated files beyond Options.MaxBackups (oldest
+// first) and those older than Options.MaxAge. Must be called with w.mu held.
+func (w *Writer) cleanupBackups() error {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(w.filename)
+	base := filepath.Base(w.filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("list log directory for cleanup: %w", err)
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), base+".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{filepath.Join(dir, e.Name()), info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+
+	var firstErr error
+	note := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if w.opts.MaxAge > 0 {
+		cutoff := time.Now().Add(-w.opts.MaxAge)
+		var kept []backup
+		for _, b := range backups {
+			if b.modTime.Before(cutoff) {
+				note(os.Remove(b.path))
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	if w.opts.MaxBackups > 0 && len(backups) > w.opts.MaxBackups {
+		for _, b := range backups[w.opts.MaxBackups:] {
+			note(os.Remove(b.path))
+		}
+	}
+
+	return firstErr
+}
+
+// Close flushes and closes the current file, and stops watching for SIGHUP.
+func (w *Writer) Close() error {
+	w.stopHUP()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	if err := w.f.Sync(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
@@ -0,0 +1,108 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w, err := New(name, Options{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("0123456789\n")); err != nil {
+			t.Fatalf("write: %s", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("readdir: %s", err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("got %d files, expected at least 2 (current + backups)", len(entries))
+	}
+}
+
+func TestMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w, err := New(name, Options{MaxSize: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer w.Close()
+
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("x\n")); err != nil {
+			t.Fatalf("write: %s", err)
+		}
+		time.Sleep(time.Millisecond) // Ensure distinct mtimes for ordering.
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("readdir: %s", err)
+	}
+	// The current file plus at most MaxBackups rotated files.
+	if len(entries) > 3 {
+		t.Fatalf("got %d files, expected at most 3 (current + 2 backups)", len(entries))
+	}
+}
+
+func TestCompress(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	w, err := New(name, Options{MaxSize: 1, Compress: true})
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("write: %s", err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("rotate: %s", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("readdir: %s", err)
+	}
+	var found bool
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".gz") {
+			found = true
+			f, err := os.Open(filepath.Join(dir, e.Name()))
+			if err != nil {
+				t.Fatalf("open gz: %s", err)
+			}
+			gr, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatalf("gzip.NewReader: %s", err)
+			}
+			data, err := io.ReadAll(gr)
+			if err != nil {
+				t.Fatalf("read gz: %s", err)
+			}
+			if string(data) != "hello\n" {
+				t.Fatalf("got %q, expected %q", data, "hello\n")
+			}
+			f.Close()
+		}
+	}
+	if !found {
+		t.Fatalf("no .gz backup found among %v", entries)
+	}
+}
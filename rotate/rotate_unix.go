@@ -0,0 +1,56 @@
+//go:build !windows && !plan9 && !js
+
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// unixState holds the pieces needed to stop watching for SIGHUP again.
+type unixState struct {
+	sig  chan os.Signal
+	done chan struct{}
+}
+
+// watchHUP starts a goroutine that reopens the current file on each SIGHUP,
+// for compatibility with external logrotate-style tools.
+func (w *Writer) watchHUP() {
+	w.unix.sig = make(chan os.Signal, 1)
+	w.unix.done = make(chan struct{})
+	signal.Notify(w.unix.sig, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-w.unix.sig:
+				if err := w.reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "reopen log file on SIGHUP: %s\n", err)
+				}
+			case <-w.unix.done:
+				return
+			}
+		}
+	}()
+}
+
+// reopen opens filename again (creating it if it was moved away) and closes
+// the previously open file.
+func (w *Writer) reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.f
+	if err := w.openCurrent(); err != nil {
+		return err
+	}
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+func (w *Writer) stopHUP() {
+	signal.Stop(w.unix.sig)
+	close(w.unix.done)
+}
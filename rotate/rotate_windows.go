@@ -0,0 +1,11 @@
+//go:build windows || plan9 || js
+
+package rotate
+
+// unixState is unused outside unix-like systems; SIGHUP has no equivalent
+// here.
+type unixState struct{}
+
+func (w *Writer) watchHUP() {}
+
+func (w *Writer) stopHUP() {}
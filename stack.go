@@ -0,0 +1,104 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"github.com/mjl-/log/fur"
+)
+
+// StackFrame is a single call frame captured from an xerrors.Formatter chain,
+// see FlagStack.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// collectStack walks the error chain, extracting a StackFrame for each error
+// that implements xerrors.Formatter (as xerrors.Errorf-constructed errors
+// do), using a Printer that captures only the Detail() output xerrors.Frame
+// writes. Consecutive identical frames (e.g. from %w-wrapping at the same
+// call site) are collapsed into one.
+//
+// fur.Error is unwrapped through its Err field directly rather than through
+// its Unwrap method, which skips a level (it calls xerrors.Unwrap(e.Err)
+// instead of returning e.Err) and would otherwise cause the frame of the
+// fur.Errorf call that produced e.Err to be missed.
+func collectStack(err error) []StackFrame {
+	var frames []StackFrame
+	var prevKey string
+	for err != nil {
+		if fe, ok := err.(fur.Error); ok {
+			err = fe.Err
+			continue
+		}
+		f, ok := err.(xerrors.Formatter)
+		if !ok {
+			err = xerrors.Unwrap(err)
+			continue
+		}
+		p := &framePrinter{}
+		err = f.FormatError(p)
+		frame, ok := p.frame()
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s\x00%s\x00%d", frame.Function, frame.File, frame.Line)
+		if key == prevKey {
+			continue
+		}
+		prevKey = key
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// framePrinter implements xerrors.Printer, capturing only what
+// xerrors.Frame.Format writes once Detail is requested, ignoring the error
+// message text written before it.
+type framePrinter struct {
+	inDetail bool
+	buf      strings.Builder
+}
+
+func (p *framePrinter) Print(args ...interface{}) {}
+
+func (p *framePrinter) Printf(format string, args ...interface{}) {
+	if p.inDetail {
+		fmt.Fprintf(&p.buf, format, args...)
+	}
+}
+
+func (p *framePrinter) Detail() bool {
+	p.inDetail = true
+	return true
+}
+
+// frame parses the "function\n    file:line\n" text written by
+// xerrors.Frame.Format, if any was captured.
+func (p *framePrinter) frame() (StackFrame, bool) {
+	s := strings.TrimSuffix(p.buf.String(), "\n")
+	if s == "" {
+		return StackFrame{}, false
+	}
+	function, loc, ok := strings.Cut(s, "\n    ")
+	if !ok {
+		// Only one of function/file:line was written.
+		if i := strings.LastIndex(s, ":"); i >= 0 {
+			loc = s
+		} else {
+			function = s
+		}
+	}
+	var file string
+	var line int
+	if i := strings.LastIndex(loc, ":"); i >= 0 {
+		file = loc[:i]
+		line, _ = strconv.Atoi(loc[i+1:])
+	}
+	return StackFrame{Function: function, File: file, Line: line}, true
+}
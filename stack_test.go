@@ -0,0 +1,80 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"github.com/mjl-/log/fur"
+)
+
+func TestStackDisabled(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", 0)
+	l.SetFormatter(&JSONFormatter{})
+	l.Errorf("open resource: %w", errBoom)
+
+	var o map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &o); err != nil {
+		t.Fatalf("unmarshal json: %s", err)
+	}
+	if _, ok := o["stack"]; ok {
+		t.Fatalf("got stack field, expected none without FlagStack")
+	}
+}
+
+func TestStackJSON(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", FlagStack)
+	l.SetFormatter(&JSONFormatter{})
+	l.Errorf("open resource: %w", fur.Errorf("connect: %w", errBoom).Tag("address", "x"))
+
+	var o map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &o); err != nil {
+		t.Fatalf("unmarshal json: %s", err)
+	}
+	stack, ok := o["stack"].([]interface{})
+	if !ok || len(stack) == 0 {
+		t.Fatalf("got stack %v, expected a non-empty array", o["stack"])
+	}
+	frame, ok := stack[0].(map[string]interface{})
+	if !ok || frame["function"] == "" || frame["file"] == "" || frame["line"] == float64(0) {
+		t.Fatalf("got frame %v, expected function/file/line set", frame)
+	}
+}
+
+func TestStackThroughFurError(t *testing.T) {
+	inner := xerrors.Errorf("inner: %w", errBoom)
+	mid := fur.Errorf("mid: %w", inner).Tag("address", "x")
+	outer := xerrors.Errorf("outer: %w", mid)
+
+	frames := collectStack(outer)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, expected 3 (outer, mid, inner); frames: %+v", len(frames), frames)
+	}
+}
+
+func TestStackDedup(t *testing.T) {
+	err := fur.Errorf("wrap: %w", errBoom)
+	frames := collectStack(err)
+	seen := map[string]bool{}
+	for _, fr := range frames {
+		key := fr.Function + fr.File
+		if seen[key] {
+			t.Fatalf("got duplicate consecutive frame %+v", fr)
+		}
+		seen[key] = true
+	}
+}
+
+func TestStackText(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", FlagStack)
+	l.Errorf("open resource: %w", errBoom)
+
+	if !strings.Contains(buf.String(), ".go:") {
+		t.Fatalf("got %q, expected a file:line in the stack trace", buf.String())
+	}
+}
@@ -0,0 +1,66 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mjl-/log/fur"
+)
+
+func TestWith(t *testing.T) {
+	buf := &strings.Builder{}
+	base := New(buf, "", 0)
+	base.SetFormatter(&JSONFormatter{})
+	reqLogger := base.With(fur.Tags{"request_id": "r1"})
+	userLogger := reqLogger.With(fur.Tags{"request_id": "r2", "user_id": 123})
+
+	userLogger.Infof("handled request")
+
+	var o map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &o); err != nil {
+		t.Fatalf("unmarshal json: %s", err)
+	}
+	if o["request_id"] != "r2" {
+		t.Fatalf("got request_id %v, expected child tag r2 to win", o["request_id"])
+	}
+	if o["user_id"] != float64(123) {
+		t.Fatalf("got user_id %v, expected 123", o["user_id"])
+	}
+
+	// Original logger must be unaffected.
+	buf.Reset()
+	base.Infof("unrelated")
+	var o2 map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &o2); err != nil {
+		t.Fatalf("unmarshal json: %s", err)
+	}
+	if _, ok := o2["request_id"]; ok {
+		t.Fatalf("base logger picked up tags from its child: %v", o2)
+	}
+}
+
+func TestWithPairs(t *testing.T) {
+	buf := &strings.Builder{}
+	l := New(buf, "", 0).WithPairs("address", "localhost", "port")
+	l.Infof("connected")
+
+	s := buf.String()
+	if !strings.Contains(s, "address=localhost") || !strings.Contains(s, "port=!MISSING") {
+		t.Fatalf("got %q, missing expected tags", s)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if l := FromContext(context.Background()); l != std {
+		t.Fatalf("FromContext on bare context should return the default logger")
+	}
+
+	buf := &strings.Builder{}
+	l := New(buf, "", 0)
+	ctx := NewContext(context.Background(), l)
+	if FromContext(ctx) != l {
+		t.Fatalf("FromContext did not return the logger stored by NewContext")
+	}
+}